@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authProvider validates Basic Auth credentials for globalHandler.
+type authProvider interface {
+	validate(user, pass string) bool
+}
+
+// newAuthProvider builds the authProvider configured by --user/--htpasswd.
+// --htpasswd takes precedence when both are set.
+func newAuthProvider(user, htpasswdPath string) (authProvider, error) {
+	if htpasswdPath != "" {
+		hf, err := newHtpasswdFile(htpasswdPath)
+		if err != nil {
+			return nil, err
+		}
+		onSIGHUP(func() {
+			log.Printf("reloading htpasswd file %s", htpasswdPath)
+			if err := hf.reload(); err != nil {
+				log.Printf("error: unable to reload htpasswd file: %s", err)
+			}
+		})
+		return hf, nil
+	}
+
+	u, p, err := splitToKeyValue(user, ":")
+	if err != nil {
+		return nil, fmt.Errorf("invalid --user: %w", err)
+	}
+	return staticCreds{user: u, pass: p}, nil
+}
+
+// staticCreds is the original single user:pass credential from --user.
+type staticCreds struct {
+	user string
+	pass string
+}
+
+func (s staticCreds) validate(user, pass string) bool {
+	return s.user == user && s.pass == pass
+}
+
+// htpasswdFile validates against a standard Apache htpasswd file, reloaded
+// on demand via reload(). bcrypt, {SHA} and MD5-crypt ($apr1$/$1$) entries
+// are supported; legacy crypt(3) DES entries are not.
+type htpasswdFile struct {
+	path string
+
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+func newHtpasswdFile(path string) (*htpasswdFile, error) {
+	h := &htpasswdFile{path: path}
+	if err := h.reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *htpasswdFile) reload() error {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return fmt.Errorf("unable to open htpasswd file: %w", err)
+	}
+	defer quietClose(f)
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, err := splitToKeyValue(line, ":")
+		if err != nil {
+			continue
+		}
+		entries[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read htpasswd file: %w", err)
+	}
+
+	h.mu.Lock()
+	h.entries = entries
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *htpasswdFile) validate(user, pass string) bool {
+	h.mu.RLock()
+	hash, ok := h.entries[user]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	return verifyHtpasswdHash(hash, pass)
+}
+
+func verifyHtpasswdHash(hash, pass string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2y$"), strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(pass))
+		return hash == "{SHA}"+base64.StdEncoding.EncodeToString(sum[:])
+	case strings.HasPrefix(hash, "$apr1$"), strings.HasPrefix(hash, "$1$"):
+		return md5CryptVerify(pass, hash)
+	default:
+		return false
+	}
+}
+
+// md5CryptVerify checks pass against an md5-crypt hash in "$magic$salt$digest"
+// form, where magic is "1" (standard crypt) or "apr1" (Apache htpasswd).
+func md5CryptVerify(pass, hash string) bool {
+	parts := strings.SplitN(hash, "$", 4)
+	if len(parts) != 4 {
+		return false
+	}
+	magic, salt := parts[1], parts[2]
+	return md5Crypt(pass, salt, magic) == hash
+}
+
+const md5CryptItoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// md5Crypt implements the FreeBSD/Apache MD5-crypt algorithm used by
+// "$1$"/"$apr1$" htpasswd entries.
+func md5Crypt(password, salt, magic string) string {
+	pw := []byte(password)
+	sa := []byte(salt)
+
+	d := md5.New()
+	d.Write(pw)
+	d.Write([]byte("$" + magic + "$"))
+	d.Write(sa)
+
+	d2 := md5.New()
+	d2.Write(pw)
+	d2.Write(sa)
+	d2.Write(pw)
+	mixin := d2.Sum(nil)
+
+	for pl := len(pw); pl > 0; pl -= 16 {
+		if pl > 16 {
+			d.Write(mixin)
+		} else {
+			d.Write(mixin[0:pl])
+		}
+	}
+
+	for i := len(pw); i != 0; i >>= 1 {
+		if i&1 != 0 {
+			d.Write([]byte{0})
+		} else {
+			d.Write(pw[0:1])
+		}
+	}
+
+	final := d.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		d2 := md5.New()
+		if i&1 != 0 {
+			d2.Write(pw)
+		} else {
+			d2.Write(final)
+		}
+		if i%3 != 0 {
+			d2.Write(sa)
+		}
+		if i%7 != 0 {
+			d2.Write(pw)
+		}
+		if i&1 != 0 {
+			d2.Write(final)
+		} else {
+			d2.Write(pw)
+		}
+		final = d2.Sum(nil)
+	}
+
+	encode3 := func(b *strings.Builder, a, b2, c byte) {
+		v := uint(a)<<16 | uint(b2)<<8 | uint(c)
+		for i := 0; i < 4; i++ {
+			b.WriteByte(md5CryptItoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	encode1 := func(b *strings.Builder, a byte) {
+		v := uint(a)
+		for i := 0; i < 2; i++ {
+			b.WriteByte(md5CryptItoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+
+	var out strings.Builder
+	out.WriteByte('$')
+	out.WriteString(magic)
+	out.WriteByte('$')
+	out.WriteString(salt)
+	out.WriteByte('$')
+	encode3(&out, final[0], final[6], final[12])
+	encode3(&out, final[1], final[7], final[13])
+	encode3(&out, final[2], final[8], final[14])
+	encode3(&out, final[3], final[9], final[15])
+	encode3(&out, final[4], final[10], final[5])
+	encode1(&out, final[11])
+
+	return out.String()
+}
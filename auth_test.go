@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func Test_verifyHtpasswdHash(t *testing.T) {
+	type args struct {
+		hash string
+		pass string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"bcrypt_2a_match", args{"$2a$04$ofN5g4w48HTSR5R2dEePV.qHsJ9pqwdlVAzR9xHGirf3GZMwQgiAG", "hunter2"}, true},
+		{"bcrypt_2a_mismatch", args{"$2a$04$ofN5g4w48HTSR5R2dEePV.qHsJ9pqwdlVAzR9xHGirf3GZMwQgiAG", "wrong"}, false},
+		{"sha_match", args{"{SHA}87u9ZqY9S/F0eUBXjsPQEDUw4h0=", "hunter2"}, true},
+		{"sha_mismatch", args{"{SHA}87u9ZqY9S/F0eUBXjsPQEDUw4h0=", "wrong"}, false},
+		{"apr1_match", args{"$apr1$NaCl1234$UHtnFHjibJgihf8nOCYtI/", "hunter2"}, true},
+		{"apr1_mismatch", args{"$apr1$NaCl1234$UHtnFHjibJgihf8nOCYtI/", "wrong"}, false},
+		{"md5crypt_match", args{"$1$NaCl1234$DTJ23LaJdBrzYjsvu6hXP/", "hunter2"}, true},
+		{"md5crypt_mismatch", args{"$1$NaCl1234$DTJ23LaJdBrzYjsvu6hXP/", "wrong"}, false},
+		{"unsupported_des_crypt", args{"abZjZJ3Ykq8Bw", "hunter2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyHtpasswdHash(tt.args.hash, tt.args.pass); got != tt.want {
+				t.Errorf("verifyHtpasswdHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_md5Crypt(t *testing.T) {
+	// Reference values cross-checked against `openssl passwd -apr1`/`-1`.
+	type args struct {
+		password string
+		salt     string
+		magic    string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"apr1", args{"password", "abcdefgh", "apr1"}, "$apr1$abcdefgh$FBwExRW4dCc8aL.OvjpIE1"},
+		{"crypt_1", args{"password", "abcdefgh", "1"}, "$1$abcdefgh$G//4keteveJp0qb8z2DxG/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := md5Crypt(tt.args.password, tt.args.salt, tt.args.magic); got != tt.want {
+				t.Errorf("md5Crypt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_staticCreds_validate(t *testing.T) {
+	creds := staticCreds{user: "alice", pass: "s3cret"}
+	if !creds.validate("alice", "s3cret") {
+		t.Errorf("validate() = false, want true for correct credentials")
+	}
+	if creds.validate("alice", "wrong") {
+		t.Errorf("validate() = true, want false for wrong password")
+	}
+	if creds.validate("bob", "s3cret") {
+		t.Errorf("validate() = true, want false for wrong user")
+	}
+}
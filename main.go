@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/cgi"
+	"net/http/fcgi"
 	"net/http/httputil"
 	"os"
 	"os/signal"
@@ -19,6 +22,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/spf13/pflag"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 var (
@@ -33,11 +37,32 @@ var (
 	certFileFlag        string
 	keyFileFlag         string
 	userFlag            string
+	cgiPathFlag         string
+	cgiDirFlag          string
+	fcgiFlag            bool
+	acmeHostsFlag       string
+	acmeCacheDirFlag    string
+	acmeEmailFlag       string
+	proxyUpstreamFlag   string
+	rulesFileFlag       string
+	chunkSizeFlag       uint
+	chunkIntervalFlag   time.Duration
+	delayFlag           time.Duration
+	htpasswdFileFlag    string
 
 	responseCount uint = 0
 	absBaseDir    string
+
+	reloadHandlers []func()
+	activeAuth     authProvider
 )
 
+// onSIGHUP registers f to run whenever the process receives SIGHUP, letting
+// flags like --rules/--htpasswd be reloaded without a restart.
+func onSIGHUP(f func()) {
+	reloadHandlers = append(reloadHandlers, f)
+}
+
 func main() {
 
 	pflag.BoolVar(&versionFlag, "version", false, "show version")
@@ -49,6 +74,18 @@ func main() {
 	pflag.UintVarP(&exitAfterFlag, "count", "c", 0, "exit after number of requests (0 keep running)")
 	pflag.StringVar(&certFileFlag, "cert", "", "TLS certificate file")
 	pflag.StringVarP(&userFlag, "user", "u", "", "user credentials '<user:password>' for Basic Auth")
+	pflag.StringVar(&cgiPathFlag, "cgi", "", "path to a CGI executable to serve requests through")
+	pflag.StringVar(&cgiDirFlag, "cgi-dir", "", "URL path prefix to strip before splitting PATH_INFO/SCRIPT_NAME (net/http/cgi Root)")
+	pflag.BoolVar(&fcgiFlag, "fcgi", false, "serve the configured response over FastCGI instead of plain HTTP")
+	pflag.StringVar(&acmeHostsFlag, "acme", "", "comma-separated hostnames to obtain a TLS certificate for via ACME/Let's Encrypt")
+	pflag.StringVar(&acmeCacheDirFlag, "acme-cache", "acme-cache", "directory to cache ACME certificates in")
+	pflag.StringVar(&acmeEmailFlag, "acme-email", "", "contact email address registered with the ACME CA")
+	pflag.StringVar(&proxyUpstreamFlag, "proxy", "", "upstream URL to reverse-proxy requests to, logging both sides")
+	pflag.StringVar(&rulesFileFlag, "rules", "", "path to a YAML or JSON rules file for scripted per-request responses")
+	pflag.UintVar(&chunkSizeFlag, "chunk-size", 0, "stream the response body in chunks of this many bytes")
+	pflag.DurationVar(&chunkIntervalFlag, "chunk-interval", 0, "pause this long between response chunks")
+	pflag.DurationVar(&delayFlag, "delay", 0, "pause this long before writing the response body")
+	pflag.StringVar(&htpasswdFileFlag, "htpasswd", "", "htpasswd file (bcrypt/SHA/MD5-crypt) for multi-user Basic Auth")
 
 	pflag.Usage = func() {
 		_, _ = fmt.Fprintf(os.Stderr, "Usage: %s [options...] <addr>\n%s", filepath.Base(os.Args[0]),
@@ -88,17 +125,92 @@ func main() {
 		}
 	}
 
+	if rulesFileFlag != "" {
+		if err := loadRules(rulesFileFlag); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		onSIGHUP(func() {
+			log.Printf("reloading rules from %s", rulesFileFlag)
+			if err := loadRules(rulesFileFlag); err != nil {
+				log.Printf("error: unable to reload rules: %s", err)
+			}
+		})
+	}
+
+	if userFlag != "" || htpasswdFileFlag != "" {
+		activeAuth, err = newAuthProvider(userFlag, htpasswdFileFlag)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(reloadHandlers) != 0 {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		go func() {
+			for range hupChan {
+				for _, h := range reloadHandlers {
+					h()
+				}
+			}
+		}()
+	}
+
 	srv := http.Server{Addr: addr}
-	description := fmt.Sprintf("surl/%s", version)
+	description := serverDescription()
+
+	var acmeChallengeSrv *http.Server
+	if acmeHostsFlag != "" {
+		hosts := parseAcmeHosts(acmeHostsFlag)
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(acmeCacheDirFlag),
+			Email:      acmeEmailFlag,
+		}
+		srv.TLSConfig = m.TLSConfig()
+		acmeChallengeSrv = &http.Server{Addr: ":80", Handler: m.HTTPHandler(nil)}
+		go func() {
+			if err := acmeChallengeSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("error: acme challenge server: %s", err)
+			}
+		}()
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	http.Handle("/", requestLogger(globalHandler(description, sigChan)))
 
+	// --fcgi swaps the regular net/http server loop below for fcgi.Serve on
+	// the same listener; there's no parsing or decision logic here worth
+	// unit-testing in isolation; it's a straight substitution of transports
+	// exercised end-to-end by running surl --fcgi against a FastCGI client.
+	var fcgiListener net.Listener
+	if fcgiFlag {
+		fcgiListener, err = net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("startup error: %v", err)
+		}
+	}
+
 	go func() {
 		log.Printf("starting %s on %s %s", description, addr, desc(exitAfterFlag))
 		if absBaseDir != "" {
 			log.Printf("serving files from: %s", absBaseDir)
 		}
+		if fcgiFlag {
+			if err := fcgi.Serve(fcgiListener, nil); err != nil && !errors.Is(err, net.ErrClosed) {
+				log.Fatalf("startup error: %v", err)
+			}
+			return
+		}
+		if acmeHostsFlag != "" {
+			if err := srv.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("startup error: %v", err)
+			}
+			return
+		}
 		if certFileFlag != "" && keyFileFlag != "" {
 			if err := srv.ListenAndServeTLS(certFileFlag, keyFileFlag); !errors.Is(err, http.ErrServerClosed) {
 				log.Fatalf("startup error: %v", err)
@@ -114,13 +226,19 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownRelease()
-
 	if exitAfterFlag != responseCount {
 		log.Printf("shutting down after %d responses", responseCount)
 	}
-	err = srv.Shutdown(shutdownCtx)
+	if fcgiFlag {
+		err = fcgiListener.Close()
+	} else {
+		shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownRelease()
+		err = srv.Shutdown(shutdownCtx)
+		if acmeChallengeSrv != nil {
+			_ = acmeChallengeSrv.Shutdown(shutdownCtx)
+		}
+	}
 	if err != nil {
 		log.Fatalf("shutdown error: %v", err)
 	}
@@ -142,6 +260,11 @@ func (crw *collectingResponseWriter) Write(b []byte) (int, error) {
 	crw.size += n
 	return n, err
 }
+func (crw *collectingResponseWriter) Flush() {
+	if f, ok := crw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
 
 func requestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -164,7 +287,7 @@ func requestLogger(next http.Handler) http.Handler {
 			r.Referer(),
 			r.UserAgent(),
 			time.Since(started),
-			logDump(logData),
+			logDump(logData)+logProxy(logData),
 		)
 	})
 }
@@ -193,6 +316,9 @@ func logPath(r *http.Request, logdata map[string]any) string {
 		if s, ok := logdata["served-file"]; ok && s != "" {
 			p += fmt.Sprintf(" (%s)", s)
 		}
+		if s, ok := logdata["rule"]; ok && s != "" {
+			p += fmt.Sprintf(" (rule:%s)", s)
+		}
 	}
 	return p
 }
@@ -205,8 +331,9 @@ func addLogData(r *http.Request, key string, value any) {
 
 func globalHandler(description string, sigChan chan os.Signal) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if userFlag != "" {
-			if !validateBasicAuth(r, userFlag) {
+		if activeAuth != nil {
+			user, pass, ok := r.BasicAuth()
+			if !ok || !activeAuth.validate(user, pass) {
 				w.Header().Add("WWW-Authenticate", "Basic realm=\"Auth Required\"")
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
@@ -225,16 +352,36 @@ func globalHandler(description string, sigChan chan os.Signal) http.Handler {
 			// log.Printf("\n--\n%q\n--\n", dump)
 		}
 
-		if len(responseHeadersFlag) != 0 {
-			for _, hdr := range responseHeadersFlag {
-				if err := addRawHeader(w.Header(), hdr); err != nil {
-					log.Printf("error: unable to add response header: %s", err)
+		// --proxy applies --header/Server itself, inside ModifyResponse, once
+		// the upstream response is in hand; applying them here too would add
+		// them twice (and clash with the upstream's own Server header).
+		if proxyUpstreamFlag == "" {
+			if len(responseHeadersFlag) != 0 {
+				for _, hdr := range responseHeadersFlag {
+					if err := addRawHeader(w.Header(), hdr); err != nil {
+						log.Printf("error: unable to add response header: %s", err)
+					}
 				}
 			}
+
+			if w.Header().Get("Server") == "" {
+				w.Header().Add("Server", description)
+			}
+		}
+
+		if cr := matchRule(r); cr != nil {
+			serveRuleResponse(w, r, cr)
+			return
 		}
 
-		if w.Header().Get("Server") == "" {
-			w.Header().Add("Server", description)
+		if cgiPathFlag != "" {
+			serveCGI(w, r)
+			return
+		}
+
+		if proxyUpstreamFlag != "" {
+			serveProxy(w, r)
+			return
 		}
 
 		if responseBodyFlag != "" {
@@ -276,15 +423,13 @@ func globalHandler(description string, sigChan chan os.Signal) http.Handler {
 				if w.Header().Get("Content-Length") == "" {
 					w.Header().Add("Content-Length", strconv.Itoa(int(s.Size())))
 				}
+				prepareStreamingHeaders(w.Header())
 				w.WriteHeader(int(statusCodeFlag)) // start sending body
-				if _, err = io.Copy(w, file); err != nil {
-					log.Printf("error: unable to write response body: %s", err)
-				}
+				writeResponseBody(w, file)
 			} else {
+				prepareStreamingHeaders(w.Header())
 				w.WriteHeader(int(statusCodeFlag)) // start sending body
-				if _, err := w.Write([]byte(responseBodyFlag)); err != nil {
-					log.Printf("error: unable to write response body: %s", err)
-				}
+				writeResponseBody(w, strings.NewReader(responseBodyFlag))
 			}
 		} else {
 			w.WriteHeader(int(statusCodeFlag))
@@ -297,6 +442,33 @@ func globalHandler(description string, sigChan chan os.Signal) http.Handler {
 	})
 }
 
+// cgiDefaultStatusWriter applies statusCodeFlag as the response status
+// whenever the wrapped handler writes the net/http/cgi default of 200. Since
+// net/http/cgi calls WriteHeader(200) both for its own implicit default and
+// for a script that explicitly emits "Status: 200 OK", the two cases can't be
+// told apart here: a script explicitly choosing 200 is overridden by
+// statusCodeFlag the same as a script that emitted no "Status:" header at
+// all. Only a script choosing a non-200 status is guaranteed to pass through.
+type cgiDefaultStatusWriter struct {
+	http.ResponseWriter
+}
+
+func (w *cgiDefaultStatusWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		code = int(statusCodeFlag)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func serveCGI(w http.ResponseWriter, r *http.Request) {
+	h := &cgi.Handler{
+		Path: cgiPathFlag,
+		Root: cgiDirFlag,
+		Dir:  filepath.Dir(cgiPathFlag),
+	}
+	h.ServeHTTP(&cgiDefaultStatusWriter{ResponseWriter: w}, r)
+}
+
 func validAddr(s string) error {
 	p := strings.SplitN(s, ":", 2)
 	if p == nil || len(p) != 2 {
@@ -308,22 +480,6 @@ func validAddr(s string) error {
 	return nil
 }
 
-func validateBasicAuth(r *http.Request, up string) bool {
-	if user, pass, ok := r.BasicAuth(); ok {
-		return up == user+":"+pass
-	}
-	return false
-	// ah := r.Header.Get("Authorization")
-	// if ah == "" || !strings.HasPrefix(ah, "Basic ") {
-	// 	return false
-	// }
-	// ah = strings.TrimPrefix(ah, "Basic ")
-	// if clear, err := base64.StdEncoding.DecodeString(ah); err != nil || string(clear) != up {
-	// 	return false
-	// }
-	// return true
-}
-
 func parseAddr() (string, error) {
 	if pflag.NArg() != 1 {
 		return "", fmt.Errorf("requred: 'addr'")
@@ -347,6 +503,22 @@ func trimFirst(s string) string {
 	return s[i:]
 }
 
+// serverDescription returns the value used for the synthetic Server header
+// and startup log line, shared by main() and serveProxy.
+func serverDescription() string {
+	return fmt.Sprintf("surl/%s", version)
+}
+
+// parseAcmeHosts splits the comma-separated --acme value into a trimmed
+// hostname list suitable for autocert.HostWhitelist.
+func parseAcmeHosts(s string) []string {
+	hosts := strings.Split(s, ",")
+	for i := range hosts {
+		hosts[i] = strings.TrimSpace(hosts[i])
+	}
+	return hosts
+}
+
 func splitToKeyValue(s string, sep string) (string, string, error) {
 	kv := strings.SplitN(s, sep, 2)
 	if len(kv) != 2 {
@@ -364,6 +536,72 @@ func addRawHeader(headers http.Header, rawHeader string) error {
 	return nil
 }
 
+func streamingEnabled() bool {
+	return chunkSizeFlag > 0 || chunkIntervalFlag > 0 || delayFlag > 0
+}
+
+// prepareStreamingHeaders drops Content-Length when the response is going to
+// be written in chunks, so the client falls back to chunked transfer encoding.
+func prepareStreamingHeaders(h http.Header) {
+	if streamingEnabled() {
+		h.Del("Content-Length")
+	}
+}
+
+// writeResponseBody writes body to w, honoring --delay/--chunk-size/--chunk-interval
+// when set, flushing after every chunk so clients observe it incrementally.
+func writeResponseBody(w http.ResponseWriter, body io.Reader) {
+	if !streamingEnabled() {
+		if _, err := io.Copy(w, body); err != nil {
+			log.Printf("error: unable to write response body: %s", err)
+		}
+		return
+	}
+	writeChunked(w, body, delayFlag, int(chunkSizeFlag), chunkIntervalFlag)
+}
+
+// writeChunked reads body in chunkSize-byte pieces (4096 when chunkSize <= 0),
+// sleeping delay before the first write and chunkInterval between subsequent
+// ones, flushing after every chunk so clients observe it incrementally. It is
+// shared by writeResponseBody (--chunk-size/--chunk-interval/--delay) and
+// serveRuleResponse (the equivalent per-rule fields).
+func writeChunked(w http.ResponseWriter, body io.Reader, delay time.Duration, chunkSize int, chunkInterval time.Duration) {
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	size := chunkSize
+	if size <= 0 {
+		size = 4096
+	}
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, size)
+	first := true
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if !first && chunkInterval > 0 {
+				time.Sleep(chunkInterval)
+			}
+			first = false
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				log.Printf("error: unable to write response chunk: %s", werr)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Printf("error: unable to read response body: %s", err)
+			return
+		}
+	}
+}
+
 func quietClose(c io.Closer) {
 	if err := c.Close(); err != nil {
 		log.Printf("error: unable to close: %s", err)
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_validAddr(t *testing.T) {
@@ -49,6 +53,82 @@ func Test_trimFirst(t *testing.T) {
 	}
 }
 
+func Test_parseAcmeHosts(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{"single", args{"example.com"}, []string{"example.com"}},
+		{"multiple", args{"example.com,www.example.com"}, []string{"example.com", "www.example.com"}},
+		{"trims_whitespace", args{"example.com, www.example.com"}, []string{"example.com", "www.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAcmeHosts(tt.args.s)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseAcmeHosts() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseAcmeHosts()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_cgiDefaultStatusWriter_WriteHeader(t *testing.T) {
+	defer func(s uint) { statusCodeFlag = s }(statusCodeFlag)
+	statusCodeFlag = 204
+
+	rec := httptest.NewRecorder()
+	w := &cgiDefaultStatusWriter{ResponseWriter: rec}
+	w.WriteHeader(http.StatusOK)
+	if rec.Code != 204 {
+		t.Errorf("WriteHeader(200) resulted in code %d, want statusCodeFlag override %d", rec.Code, 204)
+	}
+
+	rec = httptest.NewRecorder()
+	w = &cgiDefaultStatusWriter{ResponseWriter: rec}
+	w.WriteHeader(http.StatusNotFound)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("WriteHeader(404) resulted in code %d, want the CGI script's own status to pass through", rec.Code)
+	}
+}
+
+func Test_streamingEnabled(t *testing.T) {
+	defer func(size uint, interval, delay time.Duration) {
+		chunkSizeFlag, chunkIntervalFlag, delayFlag = size, interval, delay
+	}(chunkSizeFlag, chunkIntervalFlag, delayFlag)
+
+	chunkSizeFlag, chunkIntervalFlag, delayFlag = 0, 0, 0
+	if streamingEnabled() {
+		t.Errorf("streamingEnabled() = true, want false when no streaming flags are set")
+	}
+
+	chunkSizeFlag = 1024
+	if !streamingEnabled() {
+		t.Errorf("streamingEnabled() = false, want true when --chunk-size is set")
+	}
+
+	chunkSizeFlag, delayFlag = 0, time.Second
+	if !streamingEnabled() {
+		t.Errorf("streamingEnabled() = false, want true when --delay is set")
+	}
+}
+
+func Test_writeChunked(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeChunked(rec, strings.NewReader("hello world"), 0, 4, 0)
+	if got := rec.Body.String(); got != "hello world" {
+		t.Errorf("writeChunked() wrote %q, want %q", got, "hello world")
+	}
+}
+
 func Test_splitToKeyValue(t *testing.T) {
 	type args struct {
 		s   string
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// serveProxy forwards the request to proxyUpstreamFlag via a reverse proxy,
+// capturing the upstream response into the request's log data and applying
+// --status/--header/--data as overrides on top of whatever the upstream sent.
+func serveProxy(w http.ResponseWriter, r *http.Request) {
+	target, err := url.Parse(proxyUpstreamFlag)
+	if err != nil {
+		log.Printf("error: invalid proxy upstream %q: %s", proxyUpstreamFlag, err)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+
+		addLogData(r, "proxy-status", resp.StatusCode)
+		addLogData(r, "proxy-header", resp.Header.Clone())
+		addLogData(r, "proxy-body", body)
+
+		for _, hdr := range responseHeadersFlag {
+			if err := addRawHeader(resp.Header, hdr); err != nil {
+				log.Printf("error: unable to add response header: %s", err)
+			}
+		}
+		if resp.Header.Get("Server") == "" {
+			resp.Header.Set("Server", serverDescription())
+		}
+		if pflag.CommandLine.Changed("status") {
+			resp.StatusCode = int(statusCodeFlag)
+			resp.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		if responseBodyFlag != "" {
+			if strings.HasPrefix(responseBodyFlag, "@") {
+				data, err := os.ReadFile(trimFirst(responseBodyFlag))
+				if err != nil {
+					return fmt.Errorf("unable to read body file: %w", err)
+				}
+				body = data
+			} else {
+				body = []byte(responseBodyFlag)
+			}
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("error: proxying to %s: %s", proxyUpstreamFlag, err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	proxy.ServeHTTP(w, r)
+}
+
+// logProxy renders the captured upstream response for requestLogger, when
+// the request was served via --proxy.
+func logProxy(logData map[string]any) string {
+	status, ok := logData["proxy-status"].(int)
+	if !ok {
+		return ""
+	}
+
+	var summary bytes.Buffer
+	fmt.Fprintf(&summary, "upstream: %d", status)
+	if hdr, ok := logData["proxy-header"].(http.Header); ok {
+		for name, values := range hdr {
+			for _, v := range values {
+				fmt.Fprintf(&summary, "\nupstream-header: %s: %s", name, v)
+			}
+		}
+	}
+	if body, ok := logData["proxy-body"].([]byte); ok && len(body) > 0 {
+		fmt.Fprintf(&summary, "\nupstream-body:\n%s", hex.Dump(body))
+	}
+	return fmt.Sprintf("\n%s", indent(summary.String(), 20))
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_logProxy(t *testing.T) {
+	if got := logProxy(map[string]any{}); got != "" {
+		t.Errorf("logProxy() = %q, want empty string when no proxy-status was captured", got)
+	}
+
+	logData := map[string]any{
+		"proxy-status": 502,
+		"proxy-header": http.Header{"X-Upstream": []string{"origin-1"}},
+		"proxy-body":   []byte("boom"),
+	}
+	got := logProxy(logData)
+	for _, want := range []string{"upstream: 502", "upstream-header: X-Upstream: origin-1", "upstream-body:"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("logProxy() = %q, want it to contain %q", got, want)
+		}
+	}
+}
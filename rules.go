@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rule describes one entry in a --rules file: a matcher and the response to
+// serve when it matches.
+type rule struct {
+	Name     string       `yaml:"name" json:"name"`
+	Match    ruleMatcher  `yaml:"match" json:"match"`
+	Response ruleResponse `yaml:"response" json:"response"`
+}
+
+type ruleMatcher struct {
+	Method       string            `yaml:"method" json:"method"`
+	Path         string            `yaml:"path" json:"path"` // glob, or a regexp when prefixed with "~"
+	Headers      map[string]string `yaml:"headers" json:"headers"`
+	BodyContains string            `yaml:"body_contains" json:"body_contains"`
+}
+
+type ruleResponse struct {
+	Status        int               `yaml:"status" json:"status"`
+	Headers       map[string]string `yaml:"headers" json:"headers"`
+	Body          string            `yaml:"body" json:"body"` // literal, or "@file"
+	Delay         time.Duration     `yaml:"delay" json:"delay"`
+	ChunkSize     int               `yaml:"chunk_size" json:"chunk_size"`
+	ChunkInterval time.Duration     `yaml:"chunk_interval" json:"chunk_interval"`
+}
+
+// compiledRule is a rule with its matcher pre-compiled for repeated use.
+type compiledRule struct {
+	rule
+	pathRe *regexp.Regexp
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   []*compiledRule
+)
+
+// loadRules parses the rules file at path (YAML, or JSON when the extension
+// is .json) and atomically replaces the active rule set.
+func loadRules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read rules file: %w", err)
+	}
+
+	var parsed []rule
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return fmt.Errorf("unable to parse rules file: %w", err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			return fmt.Errorf("unable to parse rules file: %w", err)
+		}
+	}
+
+	compiled := make([]*compiledRule, 0, len(parsed))
+	for i, r := range parsed {
+		cr := &compiledRule{rule: r}
+		if strings.HasPrefix(r.Match.Path, "~") {
+			re, err := regexp.Compile(r.Match.Path[1:])
+			if err != nil {
+				return fmt.Errorf("rule %d (%s): invalid path regexp: %w", i, r.Name, err)
+			}
+			cr.pathRe = re
+		}
+		compiled = append(compiled, cr)
+	}
+
+	rulesMu.Lock()
+	rules = compiled
+	rulesMu.Unlock()
+
+	log.Printf("loaded %d rule(s) from %s", len(compiled), path)
+	return nil
+}
+
+// matchRule returns the first loaded rule matching r, or nil if none apply.
+func matchRule(r *http.Request) *compiledRule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	for _, cr := range rules {
+		if cr.Match.Method != "" && !strings.EqualFold(cr.Match.Method, r.Method) {
+			continue
+		}
+		if !matchRulePath(cr, r.URL.Path) {
+			continue
+		}
+		if !matchRuleHeaders(cr, r) {
+			continue
+		}
+		if !matchRuleBody(cr, r) {
+			continue
+		}
+		return cr
+	}
+	return nil
+}
+
+func matchRulePath(cr *compiledRule, reqPath string) bool {
+	if cr.Match.Path == "" {
+		return true
+	}
+	if cr.pathRe != nil {
+		return cr.pathRe.MatchString(reqPath)
+	}
+	ok, err := path.Match(cr.Match.Path, reqPath)
+	return err == nil && ok
+}
+
+func matchRuleHeaders(cr *compiledRule, r *http.Request) bool {
+	for name, want := range cr.Match.Headers {
+		if !strings.Contains(r.Header.Get(name), want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchRuleBody(cr *compiledRule, r *http.Request) bool {
+	if cr.Match.BodyContains == "" {
+		return true
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return false
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return bytes.Contains(body, []byte(cr.Match.BodyContains))
+}
+
+// serveRuleResponse writes the response configured on cr, honoring its
+// status, headers, optional delay and optional chunked streaming.
+func serveRuleResponse(w http.ResponseWriter, r *http.Request, cr *compiledRule) {
+	addLogData(r, "rule", cr.Name)
+
+	for name, value := range cr.Response.Headers {
+		w.Header().Set(name, value)
+	}
+
+	body := []byte(cr.Response.Body)
+	if strings.HasPrefix(cr.Response.Body, "@") {
+		data, err := os.ReadFile(trimFirst(cr.Response.Body))
+		if err != nil {
+			log.Printf("error: rule %q: unable to read body file: %s", cr.Name, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body = data
+	}
+
+	status := cr.Response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if cr.Response.Delay > 0 {
+		time.Sleep(cr.Response.Delay)
+	}
+
+	if cr.Response.ChunkSize <= 0 {
+		if w.Header().Get("Content-Length") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		w.WriteHeader(status)
+		if _, err := w.Write(body); err != nil {
+			log.Printf("error: rule %q: unable to write response body: %s", cr.Name, err)
+		}
+		return
+	}
+
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	writeChunked(w, bytes.NewReader(body), 0, cr.Response.ChunkSize, cr.Response.ChunkInterval)
+}
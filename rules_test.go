@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_matchRulePath(t *testing.T) {
+	type args struct {
+		match   ruleMatcher
+		reqPath string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"empty_matches_anything", args{ruleMatcher{}, "/anything"}, true},
+		{"glob_match", args{ruleMatcher{Path: "/api/*"}, "/api/widgets"}, true},
+		{"glob_mismatch", args{ruleMatcher{Path: "/api/*"}, "/other"}, false},
+		{"regexp_match", args{ruleMatcher{Path: "~^/api/[0-9]+$"}, "/api/42"}, true},
+		{"regexp_mismatch", args{ruleMatcher{Path: "~^/api/[0-9]+$"}, "/api/abc"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &compiledRule{rule: rule{Match: tt.args.match}}
+			if strings.HasPrefix(tt.args.match.Path, "~") {
+				cr.pathRe = regexp.MustCompile(tt.args.match.Path[1:])
+			}
+			if got := matchRulePath(cr, tt.args.reqPath); got != tt.want {
+				t.Errorf("matchRulePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchRuleHeaders(t *testing.T) {
+	cr := &compiledRule{rule: rule{Match: ruleMatcher{Headers: map[string]string{"X-Env": "prod"}}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Env", "prod-west")
+	if !matchRuleHeaders(cr, req) {
+		t.Errorf("matchRuleHeaders() = false, want true for header containing wanted substring")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Env", "staging")
+	if matchRuleHeaders(cr, req) {
+		t.Errorf("matchRuleHeaders() = true, want false for mismatched header value")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	if matchRuleHeaders(cr, req) {
+		t.Errorf("matchRuleHeaders() = true, want false for missing header")
+	}
+}
+
+func Test_matchRuleBody(t *testing.T) {
+	cr := &compiledRule{rule: rule{Match: ruleMatcher{BodyContains: "ping"}}}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("ping pong"))
+	if !matchRuleBody(cr, req) {
+		t.Errorf("matchRuleBody() = false, want true when body contains substring")
+	}
+	// the request body must still be readable downstream after matching
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "ping pong" {
+		t.Errorf("matchRuleBody() drained body = %q, want %q", body, "ping pong")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("pong"))
+	if matchRuleBody(cr, req) {
+		t.Errorf("matchRuleBody() = true, want false when body lacks substring")
+	}
+
+	cr = &compiledRule{rule: rule{Match: ruleMatcher{}}}
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("anything"))
+	if !matchRuleBody(cr, req) {
+		t.Errorf("matchRuleBody() = false, want true when BodyContains is unset")
+	}
+}